@@ -21,22 +21,47 @@ import (
 	"net"
 	"os"
 	"strconv"
+	"sync"
+	"syscall"
 	"text/template"
 	"time"
 
-	"github.com/coreos/etcd/clientv3"
 	"github.com/coreswitch/process"
+	"github.com/hashicorp/go-hclog"
 	"github.com/mitchellh/mapstructure"
 	"github.com/twinj/uuid"
+	"go.etcd.io/etcd/client/v3"
 	"golang.org/x/net/context"
 )
 
+// logger is the package-level logger for the VRRP subsystem and process
+// supervisor. It defaults to an hclog wrapper around the stdlib logger so
+// existing deployments keep seeing output on stderr without any config
+// changes; SetLogger lets operators swap in a structured sink.
+var logger hclog.Logger = hclog.FromStandardLogger(log.New(os.Stderr, "", log.LstdFlags), &hclog.LoggerOptions{
+	Name: "vrrp",
+})
+
+// SetLogger overrides the logger used by the VRRP subsystem and process
+// supervisor, so operators can route zebra's VRRP output through
+// journald/Loki as structured fields and control verbosity without
+// recompiling.
+func SetLogger(l hclog.Logger) {
+	logger = l
+}
+
 type VrrpConfig []Vrrp
 
 type VrrpInstance struct {
 	IfName  string
 	VrId    uint8
 	Process *process.Process
+
+	// LastApplied caches the Vrrp struct last written to disk for this
+	// instance, so VrrpJsonConfig (which VrrpVrfSync reaches through
+	// Commit) can cheaply diff against it instead of tearing the instance
+	// down on every commit.
+	LastApplied *Vrrp
 }
 
 var VrrpInstanceMap = map[string][]*VrrpInstance{}
@@ -62,14 +87,14 @@ func VrrpServerStart(config string, pid string, vrrpPid string, vrf string) *pro
 const vrrpConfigTemplateText = `# Do not edit!
 # This file is automatically generated from OpenConfigd.
 #
-vrrp_script bgp_track {
-    script /usr/bin/keepalived_track.sh
-    interval 1
-    fall 3
-    rise 3
-{{if .Preempt}}    weight 50{{end}}
+{{range $i, $ts := .TrackScripts}}vrrp_script {{$ts.Name}} {
+    script {{$ts.Path}}
+    interval {{if eq $ts.Interval 0}}1{{else}}{{$ts.Interval}}{{end}}
+    fall {{if eq $ts.Fall 0}}3{{else}}{{$ts.Fall}}{{end}}
+    rise {{if eq $ts.Rise 0}}3{{else}}{{$ts.Rise}}{{end}}
+{{if $ts.Weight}}    weight {{$ts.Weight}}{{end}}
 }
-
+{{end}}
 vrrp_instance {{.Name}} {
     notify /usr/bin/keepalived_{{.State}}_{{.Vrf}}.sh
     state {{if eq .State "master"}}MASTER{{else}}BACKUP{{end}}
@@ -80,55 +105,195 @@ vrrp_instance {{.Name}} {
     use_vmac
     vmac_xmit_base
 {{if not .Preempt}}    nopreempt{{end}}
-    unicast_peer {
+{{if eq .Family "ipv6"}}    native_ipv6
+{{end}}    unicast_peer {
 {{range $j, $w := .UnicastPeerList}}        {{$w.Address}}
 {{end}}
     }
     virtual_ipaddress {
-        {{.VirtualAddress}} dev {{.Interface}}
+{{range $j, $addr := .VirtualAddresses}}        {{$addr}} dev {{$.Interface}}
+{{end}}
     }
     track_script {
-        bgp_track
-    }
+{{range $i, $ts := .TrackScripts}}        {{$ts.Name}}
+{{end}}    }
     track_interface {
         {{.Interface}}
-    }
+{{range $i, $ifName := .TrackInterfaces}}        {{$ifName}}
+{{end}}    }
 }
 `
 
+// VrrpTrackScript is one vrrp_script entry a Vrrp instance tracks,
+// adjusting its priority by Weight when the script's exit status
+// changes. It mirrors keepalived's vrrp_script stanza directly.
+type VrrpTrackScript struct {
+	Name     string
+	Path     string
+	Interval int
+	Fall     int
+	Rise     int
+	Weight   int
+}
+
 func VrrpServerExec(vrrp *Vrrp, vrf string) *process.Process {
-	configFileName := fmt.Sprintf("/etc/keepalived/keepalived-%s.conf", vrrp.Interface)
-	pidFileName := fmt.Sprintf("/var/run/keepalived-%s.pid", vrrp.Interface)
-	vrrpPidFileName := fmt.Sprintf("/var/run/keepalived_vrrp-%s.pid", vrrp.Interface)
+	configFileName, pidFileName, vrrpPidFileName, err := vrrpWriteConfig(vrrp, vrf)
+	if err != nil {
+		logger.Error("create config file failed", "vrf", vrf, "ifname", vrrp.Interface, "vrid", vrrp.Vrid, "err", err)
+		return nil
+	}
+
+	return VrrpServerStart(configFileName, pidFileName, vrrpPidFileName, vrf)
+}
+
+// vrrpWriteConfig renders vrrp's keepalived.conf and notify symlinks to
+// disk, returning the paths VrrpServerStart needs to spawn the process.
+// It is also used by VrrpServerReload, which writes the same files but
+// signals the existing process instead of respawning it.
+func vrrpWriteConfig(vrrp *Vrrp, vrf string) (configFileName, pidFileName, vrrpPidFileName string, err error) {
+	vrrp.Family = vrrpEffectiveFamily(vrrp.Family)
+	vrrp.TrackScripts = vrrpEffectiveTrackScripts(vrrp)
+	if err := vrrpValidateFamily(vrrp); err != nil {
+		return "", "", "", fmt.Errorf("invalid family configuration: %v", err)
+	}
+
+	configFileName = fmt.Sprintf("/etc/keepalived/keepalived-%s.conf", vrrp.Interface)
+	pidFileName = fmt.Sprintf("/var/run/keepalived-%s.pid", vrrp.Interface)
+	vrrpPidFileName = fmt.Sprintf("/var/run/keepalived_vrrp-%s.pid", vrrp.Interface)
 	srcFileName := fmt.Sprintf("/usr/bin/keepalived_%s.sh", vrrp.State)
 	dstFileName := fmt.Sprintf("/usr/bin/keepalived_%s_%s.sh", vrrp.State, vrf)
 	os.Remove(dstFileName)
 	os.Symlink(srcFileName, dstFileName)
 
-	fmt.Println(configFileName, pidFileName)
+	logger.Info("writing keepalived config", "vrf", vrf, "ifname", vrrp.Interface, "vrid", vrrp.Vrid, "config", configFileName)
 
 	f, err := os.Create(configFileName)
 	if err != nil {
-		log.Println("Create file:", err)
-		return nil
+		return "", "", "", err
 	}
+	defer f.Close()
 	tmpl := template.Must(template.New("vrrpTemplate").Parse(vrrpConfigTemplateText))
 
 	vrrp.Vrf = vrf
-	vrrp.Name = "vrrp" + strconv.Itoa(int(vrrp.Vrid)) + "-" + vrrp.Interface + "-" + LocalCidrLookup(vrrp.Interface)
-	tmpl.Execute(f, vrrp)
+	vrrp.Name = "vrrp" + strconv.Itoa(int(vrrp.Vrid)) + "-" + vrrp.Interface + "-" + LocalCidrLookup(vrrp.Interface, vrrp.Family)
+	if err := tmpl.Execute(f, vrrp); err != nil {
+		return "", "", "", err
+	}
 
-	return VrrpServerStart(configFileName, pidFileName, vrrpPidFileName, vrf)
+	return configFileName, pidFileName, vrrpPidFileName, nil
+}
+
+// VrrpServerReload rewrites instance's keepalived.conf for vrrp and asks
+// the already-running keepalived process to reload it via SIGHUP,
+// instead of VrrpServerExec's unregister-then-respawn, which causes a
+// transient MASTER->BACKUP flap on every commit.
+func VrrpServerReload(vrrp *Vrrp, vrf string, instance *VrrpInstance) error {
+	_, _, _, err := vrrpWriteConfig(vrrp, vrf)
+	if err != nil {
+		logger.Error("write config failed", "vrf", vrf, "ifname", vrrp.Interface, "vrid", vrrp.Vrid, "err", err)
+		return err
+	}
+	if instance.Process == nil || instance.Process.Pid == 0 {
+		return fmt.Errorf("no running keepalived process for vrid %d", vrrp.Vrid)
+	}
+	if err := syscall.Kill(instance.Process.Pid, syscall.SIGHUP); err != nil {
+		logger.Error("SIGHUP failed", "vrf", vrf, "ifname", vrrp.Interface, "vrid", vrrp.Vrid, "err", err)
+		return err
+	}
+	return nil
+}
+
+// vrrpEffectiveFamily returns family, defaulting an unset family to
+// "ipv4" the same way vrrpWriteConfig does, so callers comparing two
+// Vrrp structs don't misread "never configured" as a family change.
+func vrrpEffectiveFamily(family string) string {
+	if family == "" {
+		return "ipv4"
+	}
+	return family
+}
+
+// vrrpEffectiveTrackScripts returns vrrp.TrackScripts, falling back to the
+// bgp_track script this package always ran before TrackScripts became
+// configurable. Without this, a Vrrp decoded from an old commit that never
+// set TrackScripts would silently lose health tracking altogether.
+func vrrpEffectiveTrackScripts(vrrp *Vrrp) []VrrpTrackScript {
+	if len(vrrp.TrackScripts) != 0 {
+		return vrrp.TrackScripts
+	}
+	weight := 0
+	if vrrp.Preempt {
+		weight = 50
+	}
+	return []VrrpTrackScript{
+		{
+			Name:     "bgp_track",
+			Path:     "/usr/bin/keepalived_track.sh",
+			Interval: 1,
+			Fall:     3,
+			Rise:     3,
+			Weight:   weight,
+		},
+	}
+}
+
+// vrrpValidateFamily checks that every virtual address and unicast peer
+// configured for vrrp belongs to vrrp.Family; without this check a
+// mismatch produces a keepalived process that starts but never converges.
+func vrrpValidateFamily(vrrp *Vrrp) error {
+	wantV6 := vrrp.Family == "ipv6"
+
+	for _, addr := range vrrp.VirtualAddresses {
+		ip, err := vrrpParseAddr(addr)
+		if err != nil {
+			return fmt.Errorf("virtual address %q is not a valid IP: %v", addr, err)
+		}
+		if isIPv6(ip) != wantV6 {
+			return fmt.Errorf("virtual address %q does not match family %q", addr, vrrp.Family)
+		}
+	}
+	for _, peer := range vrrp.UnicastPeerList {
+		ip, err := vrrpParseAddr(peer.Address)
+		if err != nil {
+			return fmt.Errorf("unicast peer %q is not a valid IP: %v", peer.Address, err)
+		}
+		if isIPv6(ip) != wantV6 {
+			return fmt.Errorf("unicast peer %q does not match family %q", peer.Address, vrrp.Family)
+		}
+	}
+	return nil
+}
+
+// vrrpParseAddr parses addr as either a bare IP or, since keepalived's
+// virtual_ipaddress entries are routinely written with a prefix (e.g.
+// "10.0.0.1/24"), as a CIDR, returning just the host IP either way.
+func vrrpParseAddr(addr string) (net.IP, error) {
+	if ip := net.ParseIP(addr); ip != nil {
+		return ip, nil
+	}
+	ip, _, err := net.ParseCIDR(addr)
+	if err != nil {
+		return nil, err
+	}
+	return ip, nil
+}
+
+func isIPv6(ip net.IP) bool {
+	return ip.To4() == nil
 }
 
-func LocalCidrLookup(ifName string) string {
-	addrConfig := configActive.LookupByPath([]string{"interfaces", "interface", ifName, "ipv4", "address"})
+func LocalCidrLookup(ifName string, family string) string {
+	path := []string{"interfaces", "interface", ifName, "ipv4", "address"}
+	if family == "ipv6" {
+		path = []string{"interfaces", "interface", ifName, "ipv6", "address"}
+	}
+	addrConfig := configActive.LookupByPath(path)
 	if addrConfig != nil && len(addrConfig.Keys) > 0 {
 		_, netaddr, err := net.ParseCIDR(addrConfig.Keys[0].Name)
 		if err == nil {
 			return netaddr.String()
 		} else {
-			fmt.Println("Failed to parse CIDR for interface : ", ifName)
+			logger.Error("failed to parse CIDR for interface", "ifname", ifName, "err", err)
 		}
 
 	}
@@ -138,8 +303,19 @@ func LocalCidrLookup(ifName string) string {
 var (
 	VrrpEtcdEndpoints = []string{"http://127.0.0.1:2379"}
 	VrrpEtcdPath      = "/state/services/port/vrrp"
+
+	// VrrpStateLeaseTTL bounds how long a VrrpStateWatcher's own state
+	// entries survive after the owning zebra instance stops renewing
+	// its lease, e.g. on a crash.
+	VrrpStateLeaseTTL int64 = 10
 )
 
+// vrrpStateLease is the lease NewVrrpStateWatcher grants for this
+// instance's own state entries. VrrpStateDelete puts under it when set,
+// so etcd reclaims the entries automatically if this process crashes
+// instead of leaving them until the next explicit delete.
+var vrrpStateLease clientv3.LeaseID
+
 func VrrpServerStopAll() {
 	for _, vrfInstances := range VrrpInstanceMap {
 		for _, instance := range vrfInstances {
@@ -158,28 +334,63 @@ func VrrpJsonConfig(path []string, str string) error {
 	var jsonIntf interface{}
 	err := json.Unmarshal([]byte(str), &jsonIntf)
 	if err != nil {
-		fmt.Println("json.Unmarshal", err)
+		logger.Error("json.Unmarshal failed", "err", err)
 		return err
 	}
 	vrrpConfig := VrrpConfig{}
 	err = mapstructure.Decode(jsonIntf, &vrrpConfig)
 	if err != nil {
-		fmt.Println("mapstructure.Decode", err)
+		logger.Error("mapstructure.Decode failed", "err", err)
 		return err
 	}
 
-	fmt.Println("VrrpJsonConfig", path, vrrpConfig)
+	logger.Debug("VrrpJsonConfig", "path", path, "config", vrrpConfig)
 
 	if len(path) < 3 {
-		fmt.Println("VrrpJsonConfig: path length is small", len(path))
+		logger.Error("VrrpJsonConfig: path length is small", "path_length", len(path))
 		return nil
 	}
 	vrf := path[2]
 
 	vrfInstances := VrrpInstanceMap[vrf]
+
+	var oldVrrps []Vrrp
+	for _, instance := range vrfInstances {
+		if instance != nil && instance.LastApplied != nil {
+			oldVrrps = append(oldVrrps, *instance.LastApplied)
+		}
+	}
+
+	switch mode := vrrpConfigDiff(oldVrrps, vrrpConfig); mode {
+	case reloadNone:
+		// Config is unchanged from what's already running (e.g. an
+		// unrelated VRF triggered this commit); nothing to do. Falling
+		// through to the teardown path below would restart keepalived
+		// for no reason and cause the same MASTER->BACKUP flap this
+		// request exists to avoid.
+		return nil
+	case reloadReloadable:
+		logger.Info("reloadable change, sending SIGHUP instead of restart", "vrf", vrf)
+		for _, instance := range vrfInstances {
+			if instance == nil {
+				continue
+			}
+			for i := range vrrpConfig {
+				if vrrpConfig[i].Vrid == instance.VrId {
+					vrrp := vrrpConfig[i]
+					if err := VrrpServerReload(&vrrp, vrf, instance); err == nil {
+						instance.LastApplied = &vrrp
+					}
+					break
+				}
+			}
+		}
+		return nil
+	}
+
 	for _, instance := range vrfInstances {
 		if instance != nil {
-			fmt.Println("Vrrp: Existing instance is found clearing", instance)
+			logger.Info("existing instance found, clearing", "vrf", vrf, "ifname", instance.IfName, "vrid", instance.VrId)
 			if instance.Process != nil {
 				process.ProcessUnregister(instance.Process)
 				instance.Process = nil
@@ -189,27 +400,124 @@ func VrrpJsonConfig(path []string, str string) error {
 	}
 	VrrpInstanceMap[vrf] = []*VrrpInstance{}
 	if len(vrrpConfig) == 0 {
-		fmt.Println("VrrpJsonConfig: empty VRRP config")
+		logger.Debug("VrrpJsonConfig: empty VRRP config", "vrf", vrf)
 		return nil
 	}
-	for _, vrrp := range vrrpConfig {
-		fmt.Println("VrrpJsonConfig: config", vrrp)
-		fmt.Println("VrrpJsonConfig vrf:", vrf)
+	for i := range vrrpConfig {
+		vrrp := &vrrpConfig[i]
+		logger.Debug("VrrpJsonConfig: applying config", "vrf", vrf, "ifname", vrrp.Interface, "vrid", vrrp.Vrid, "state", vrrp.State)
 
 		instance := &VrrpInstance{
-			VrId:   vrrp.Vrid,
-			IfName: vrrp.Interface,
+			VrId:        vrrp.Vrid,
+			IfName:      vrrp.Interface,
+			LastApplied: vrrp,
 		}
 		VrrpInstanceMap[vrf] = append(VrrpInstanceMap[vrf], instance)
-		instance.Process = VrrpServerExec(&vrrp, vrf)
+		instance.Process = VrrpServerExec(vrrp, vrf)
 	}
 
 	return nil
 }
 
+// reloadMode classifies the difference between a VRF's previously
+// applied Vrrp set and a newly committed one.
+type reloadMode int
+
+const (
+	// reloadNone means nothing relevant to keepalived changed.
+	reloadNone reloadMode = iota
+	// reloadReloadable means only tunables changed (priority, advert
+	// interval, peers, VIPs); the config file can be rewritten and
+	// keepalived sent SIGHUP in place.
+	reloadReloadable
+	// reloadRestartRequired means the set of VRIDs or interfaces, or an
+	// instance's state or family, changed, so instances must be
+	// unregistered and respawned.
+	reloadRestartRequired
+)
+
+// vrrpConfigDiff classifies the change from oldVrrps to newVrrps so
+// VrrpJsonConfig can avoid tearing down and respawning keepalived (and
+// the resulting MASTER->BACKUP flap) when only tunables differ.
+func vrrpConfigDiff(oldVrrps, newVrrps []Vrrp) reloadMode {
+	if len(oldVrrps) != len(newVrrps) {
+		return reloadRestartRequired
+	}
+
+	oldByVrid := map[uint8]*Vrrp{}
+	for i := range oldVrrps {
+		oldByVrid[oldVrrps[i].Vrid] = &oldVrrps[i]
+	}
+
+	mode := reloadNone
+	for i := range newVrrps {
+		old, ok := oldByVrid[newVrrps[i].Vrid]
+		if !ok || old.Interface != newVrrps[i].Interface {
+			return reloadRestartRequired
+		}
+		// keepalived only reads state/family at process start, so a
+		// change to either requires a restart rather than a reload.
+		// Compare effective families: Family defaults to "ipv4" when
+		// left unset, both here and in vrrpWriteConfig, so a config
+		// that never sets it isn't misread as a family change.
+		if old.State != newVrrps[i].State || vrrpEffectiveFamily(old.Family) != vrrpEffectiveFamily(newVrrps[i].Family) {
+			return reloadRestartRequired
+		}
+		if !vrrpTunablesEqual(old, &newVrrps[i]) {
+			mode = reloadReloadable
+		}
+	}
+	return mode
+}
+
+// vrrpTunablesEqual reports whether a and b are equal in every field that
+// keepalived can pick up via SIGHUP reload.
+func vrrpTunablesEqual(a, b *Vrrp) bool {
+	if a.Priority != b.Priority || a.AdvertisementInterval != b.AdvertisementInterval || a.Preempt != b.Preempt {
+		return false
+	}
+	if len(a.VirtualAddresses) != len(b.VirtualAddresses) {
+		return false
+	}
+	for i := range a.VirtualAddresses {
+		if a.VirtualAddresses[i] != b.VirtualAddresses[i] {
+			return false
+		}
+	}
+	if len(a.UnicastPeerList) != len(b.UnicastPeerList) {
+		return false
+	}
+	for i := range a.UnicastPeerList {
+		if a.UnicastPeerList[i].Address != b.UnicastPeerList[i].Address {
+			return false
+		}
+	}
+	// Compare effective track scripts: TrackScripts defaults to bgp_track
+	// when left unset, both here and in vrrpWriteConfig, so a config that
+	// never sets it isn't misread as dropping health tracking.
+	aScripts, bScripts := vrrpEffectiveTrackScripts(a), vrrpEffectiveTrackScripts(b)
+	if len(aScripts) != len(bScripts) {
+		return false
+	}
+	for i := range aScripts {
+		if aScripts[i] != bScripts[i] {
+			return false
+		}
+	}
+	if len(a.TrackInterfaces) != len(b.TrackInterfaces) {
+		return false
+	}
+	for i := range a.TrackInterfaces {
+		if a.TrackInterfaces[i] != b.TrackInterfaces[i] {
+			return false
+		}
+	}
+	return true
+}
+
 // Called from etcd.
 func VrrpVrfSync(vrfId int, cfg *VrfsConfig) {
-	fmt.Println("---- VRRP:", cfg.Vrrp)
+	logger.Debug("VrrpVrfSync", "vrf", fmt.Sprintf("vrf%d", vrfId), "config", cfg.Vrrp)
 
 	vrf := fmt.Sprintf("vrf%d", vrfId)
 
@@ -239,30 +547,49 @@ func VrrpVrfSync(vrfId int, cfg *VrfsConfig) {
 		} else {
 			ExecLine(fmt.Sprintf("set vrf name vrf%d vrrp %d state backup", vrfId, vrrp.Vrid))
 		}
-		if vrrp.VirtualAddress != "" {
-			ExecLine(fmt.Sprintf("set vrf name vrf%d vrrp %d virtual-address %s", vrfId, vrrp.Vrid, vrrp.VirtualAddress))
+		if vrrp.Family != "" {
+			ExecLine(fmt.Sprintf("set vrf name vrf%d vrrp %d family %s", vrfId, vrrp.Vrid, vrrp.Family))
+		}
+		for _, addr := range vrrp.VirtualAddresses {
+			ExecLine(fmt.Sprintf("set vrf name vrf%d vrrp %d virtual-address %s", vrfId, vrrp.Vrid, addr))
 		}
 		for _, peer := range vrrp.UnicastPeerList {
 			ExecLine(fmt.Sprintf("set vrf name vrf%d vrrp %d unicast-peer %s", vrfId, vrrp.Vrid, peer.Address))
 		}
+		for _, ts := range vrrp.TrackScripts {
+			ExecLine(fmt.Sprintf("set vrf name vrf%d vrrp %d track script %s path %s", vrfId, vrrp.Vrid, ts.Name, ts.Path))
+			if ts.Interval != 0 {
+				ExecLine(fmt.Sprintf("set vrf name vrf%d vrrp %d track script %s interval %d", vrfId, vrrp.Vrid, ts.Name, ts.Interval))
+			}
+			if ts.Fall != 0 {
+				ExecLine(fmt.Sprintf("set vrf name vrf%d vrrp %d track script %s fall %d", vrfId, vrrp.Vrid, ts.Name, ts.Fall))
+			}
+			if ts.Rise != 0 {
+				ExecLine(fmt.Sprintf("set vrf name vrf%d vrrp %d track script %s rise %d", vrfId, vrrp.Vrid, ts.Name, ts.Rise))
+			}
+			if ts.Weight != 0 {
+				ExecLine(fmt.Sprintf("set vrf name vrf%d vrrp %d track script %s weight %d", vrfId, vrrp.Vrid, ts.Name, ts.Weight))
+			}
+		}
+		for _, ifName := range vrrp.TrackInterfaces {
+			ExecLine(fmt.Sprintf("set vrf name vrf%d vrrp %d track interface %s", vrfId, vrrp.Vrid, ifName))
+		}
 	}
 	Commit()
 }
 
 func VrrpVrfDelete(vrfId int) {
-	fmt.Println("VrrpVrfDelete:", vrfId)
-
 	vrf := fmt.Sprintf("vrf%d", vrfId)
+	logger.Info("VrrpVrfDelete", "vrf", vrf)
 
 	vrfInstances := VrrpInstanceMap[vrf]
 	for _, instance := range vrfInstances {
 		if instance != nil {
-			fmt.Println("Vrrp: Existing instance is found removing", instance)
+			logger.Info("existing instance found, removing", "vrf", vrf, "ifname", instance.IfName, "vrid", instance.VrId)
 			if instance.Process != nil {
 				process.ProcessUnregister(instance.Process)
 				instance.Process = nil
 			}
-			fmt.Println(fmt.Sprintf("delete vrf name vrf%d vrrp %d", vrfId, instance.VrId))
 			ExecLine(fmt.Sprintf("delete vrf name vrf%d vrrp %d", vrfId, instance.VrId))
 			Commit()
 			VrrpStateDelete(instance.IfName)
@@ -282,7 +609,7 @@ func VrrpStateDelete(ifName string) {
 	}
 	conn, err := clientv3.New(cfg)
 	if err != nil {
-		fmt.Println("VrrpStateUpdate clientv3.New:", err)
+		logger.Error("VrrpStateDelete: clientv3.New failed", "ifname", ifName, "err", err)
 		return
 	}
 	defer conn.Close()
@@ -295,7 +622,7 @@ func VrrpStateDelete(ifName string) {
 	var resp *clientv3.GetResponse
 	resp, err = conn.Get(context.Background(), VrrpEtcdPath)
 	if err != nil {
-		fmt.Println("VrrpState Get failed:", err)
+		logger.Error("VrrpStateDelete: Get failed", "ifname", ifName, "err", err)
 		return
 	}
 
@@ -305,7 +632,7 @@ func VrrpStateDelete(ifName string) {
 	for _, ev := range resp.Kvs {
 		err = json.Unmarshal(ev.Value, &vrrpStatusMap)
 		if err != nil {
-			fmt.Println("Failed to Unmarshall json: " + string(ev.Value) + "error: " + err.Error())
+			logger.Error("VrrpStateDelete: failed to unmarshal state", "ifname", ifName, "err", err)
 			return
 		}
 	}
@@ -316,12 +643,152 @@ func VrrpStateDelete(ifName string) {
 	if string(jsonstr) == "{}" {
 		_, err = conn.Delete(context.Background(), VrrpEtcdPath)
 		if err != nil {
-			fmt.Println("VrrpStateUpdate Delete:", err)
+			logger.Error("VrrpStateDelete: Delete failed", "ifname", ifName, "err", err)
 		}
 	} else {
-		_, err = conn.Put(context.Background(), VrrpEtcdPath, string(jsonstr))
+		var putOpts []clientv3.OpOption
+		if vrrpStateLease != 0 {
+			putOpts = append(putOpts, clientv3.WithLease(vrrpStateLease))
+		}
+		_, err = conn.Put(context.Background(), VrrpEtcdPath, string(jsonstr), putOpts...)
 		if err != nil {
-			fmt.Println("VrrpStateUpdate Put:", err)
+			logger.Error("VrrpStateDelete: Put failed", "ifname", ifName, "err", err)
 		}
 	}
-}
\ No newline at end of file
+}
+
+// VrrpStateCallback is invoked with the latest decoded VRRP state map
+// every time VrrpEtcdPath changes.
+type VrrpStateCallback func(map[string]*VrrpState)
+
+// VrrpStateWatcher maintains a long-lived etcd watch on VrrpEtcdPath and
+// fans out decoded state updates to a channel and any registered
+// callbacks, so callers can react to master/backup transitions without
+// polling etcd the way VrrpStateDelete's lock/get/put dance does.
+//
+// It also holds a TTL-bound lease: if the owning zebra instance crashes
+// and stops renewing it, etcd reclaims the lease and any state entries
+// put under it expire on their own instead of lingering until the next
+// VrrpStateDelete call.
+type VrrpStateWatcher struct {
+	conn    *clientv3.Client
+	leaseID clientv3.LeaseID
+	events  chan map[string]*VrrpState
+	cancel  context.CancelFunc
+
+	mu        sync.Mutex
+	callbacks []VrrpStateCallback
+}
+
+// NewVrrpStateWatcher dials etcd, grants a lease to back this instance's
+// own state entries, and starts watching VrrpEtcdPath.
+func NewVrrpStateWatcher(endpoints []string) (*VrrpStateWatcher, error) {
+	conn, err := clientv3.New(clientv3.Config{
+		Endpoints:   endpoints,
+		DialTimeout: 3 * time.Second,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	lease, err := conn.Grant(context.Background(), VrrpStateLeaseTTL)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	keepAlive, err := conn.KeepAlive(context.Background(), lease.ID)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &VrrpStateWatcher{
+		conn:    conn,
+		leaseID: lease.ID,
+		events:  make(chan map[string]*VrrpState, 16),
+		cancel:  cancel,
+	}
+
+	go w.drainKeepAlive(ctx, keepAlive)
+	go w.watch(ctx)
+
+	// VrrpStateDelete puts this instance's own state entries under the
+	// same lease, so they expire on their own if this process crashes
+	// before calling VrrpStateDelete again.
+	vrrpStateLease = lease.ID
+
+	return w, nil
+}
+
+// LeaseID returns the lease backing this watcher's own state entries, so
+// callers can Put/KeepAliveOnce against it themselves (see VrrpStateDelete).
+func (w *VrrpStateWatcher) LeaseID() clientv3.LeaseID {
+	return w.leaseID
+}
+
+func (w *VrrpStateWatcher) drainKeepAlive(ctx context.Context, keepAlive <-chan *clientv3.LeaseKeepAliveResponse) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-keepAlive:
+			if !ok {
+				// Lease expired or the etcd connection was lost; our
+				// entries will be reclaimed by etcd without further action.
+				return
+			}
+		}
+	}
+}
+
+func (w *VrrpStateWatcher) watch(ctx context.Context) {
+	wc := w.conn.Watch(ctx, VrrpEtcdPath)
+	for resp := range wc {
+		for _, ev := range resp.Events {
+			var states map[string]*VrrpState
+			if ev.Type == clientv3.EventTypeDelete {
+				// The key is gone (last entry removed or lease expired):
+				// report it as an empty state map.
+				states = map[string]*VrrpState{}
+			} else if err := json.Unmarshal(ev.Kv.Value, &states); err != nil {
+				logger.Error("VrrpStateWatcher: failed to unmarshal state", "err", err)
+				continue
+			}
+			select {
+			case w.events <- states:
+			case <-ctx.Done():
+				return
+			}
+			w.mu.Lock()
+			callbacks := w.callbacks
+			w.mu.Unlock()
+			for _, cb := range callbacks {
+				cb(states)
+			}
+		}
+	}
+}
+
+// Events returns the channel of decoded VRRP state maps observed on
+// VrrpEtcdPath.
+func (w *VrrpStateWatcher) Events() <-chan map[string]*VrrpState {
+	return w.events
+}
+
+// OnStateChange registers a callback invoked with every decoded state
+// map, in addition to the Events() channel.
+func (w *VrrpStateWatcher) OnStateChange(cb VrrpStateCallback) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.callbacks = append(w.callbacks, cb)
+}
+
+// Close cancels the watch and closes the etcd connection, letting the
+// backing lease lapse. It does not close the Events() channel, since
+// watch() may still be blocked sending to it; callers should stop
+// reading from Events() once Close() returns.
+func (w *VrrpStateWatcher) Close() {
+	w.cancel()
+	w.conn.Close()
+}
@@ -0,0 +1,68 @@
+// Copyright 2017 OpenConfigd Project.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import "testing"
+
+// TestVrrpConfigDiffReloadableOnPriorityChange mirrors what happens in
+// practice: LastApplied holds a Vrrp normalized by vrrpWriteConfig
+// (Family == "ipv4"), while a freshly decoded commit rarely sets Family
+// at all (Family == ""). Only Priority differs between the two, so this
+// must classify as reloadReloadable, not reloadRestartRequired.
+func TestVrrpConfigDiffReloadableOnPriorityChange(t *testing.T) {
+	applied := []Vrrp{
+		{Vrid: 1, Interface: "eth0", State: "master", Family: "ipv4", Priority: 100},
+	}
+	committed := []Vrrp{
+		{Vrid: 1, Interface: "eth0", State: "master", Priority: 150},
+	}
+
+	mode := vrrpConfigDiff(applied, committed)
+	if mode != reloadReloadable {
+		t.Fatalf("vrrpConfigDiff = %v, want reloadReloadable", mode)
+	}
+}
+
+func TestVrrpConfigDiffRestartOnStateChange(t *testing.T) {
+	applied := []Vrrp{
+		{Vrid: 1, Interface: "eth0", State: "master", Family: "ipv4", Priority: 100},
+	}
+	committed := []Vrrp{
+		{Vrid: 1, Interface: "eth0", State: "backup", Priority: 100},
+	}
+
+	mode := vrrpConfigDiff(applied, committed)
+	if mode != reloadRestartRequired {
+		t.Fatalf("vrrpConfigDiff = %v, want reloadRestartRequired", mode)
+	}
+}
+
+// TestVrrpConfigDiffNoneOnIdenticalConfig covers the common case of an
+// unrelated VRF triggering a re-commit: VrrpJsonConfig must treat
+// reloadNone as "do nothing" rather than falling into the teardown path,
+// or every no-op commit would flap MASTER->BACKUP too.
+func TestVrrpConfigDiffNoneOnIdenticalConfig(t *testing.T) {
+	applied := []Vrrp{
+		{Vrid: 1, Interface: "eth0", State: "master", Family: "ipv4", Priority: 100},
+	}
+	committed := []Vrrp{
+		{Vrid: 1, Interface: "eth0", State: "master", Priority: 100},
+	}
+
+	mode := vrrpConfigDiff(applied, committed)
+	if mode != reloadNone {
+		t.Fatalf("vrrpConfigDiff = %v, want reloadNone", mode)
+	}
+}